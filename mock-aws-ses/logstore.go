@@ -0,0 +1,374 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// LogRecord is a single delivered (or attempted) email, as persisted by a
+// LogStore.
+type LogRecord struct {
+	ID           int64     `json:"id"`
+	Timestamp    time.Time `json:"ts"`
+	From         string    `json:"from"`
+	To           []string  `json:"to"`
+	Subject      string    `json:"subject"`
+	Body         string    `json:"body"`
+	SESMessageID string    `json:"ses_message_id,omitempty"`
+	Status       string    `json:"status"`
+}
+
+// LogQuery filters a LogStore.Query call. A zero value matches everything,
+// subject to the default Limit applied by the store.
+type LogQuery struct {
+	From   string
+	To     string
+	Since  time.Time
+	Until  time.Time
+	Status string
+	Limit  int
+	Cursor int64 // last ID seen by the caller; results start after it
+}
+
+// LogStore persists delivered email records and serves the GET /logs query
+// API. The SQLite-backed implementation replaces the old
+// saveEmailLog/loadEmailLogs pair, which rewrote the entire JSON file on
+// every send.
+type LogStore interface {
+	Insert(record LogRecord) (int64, error)
+	Get(id int64) (*LogRecord, error)
+	FindBySESMessageID(messageID string) (*LogRecord, error)
+	Query(q LogQuery) (records []LogRecord, nextCursor int64, err error)
+	UpdateStatus(id int64, status string) error
+	ExportJSON(w io.Writer) error
+	Close() error
+}
+
+const defaultLogQueryLimit = 50
+const maxLogQueryLimit = 200
+
+// sqliteLogStore is the SQLite-backed LogStore.
+type sqliteLogStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteLogStore opens (creating if necessary) a SQLite database at path,
+// ensures the schema exists, and imports any pre-existing email_logs.json on
+// first startup.
+func NewSQLiteLogStore(path string) (*sqliteLogStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log store: %w", err)
+	}
+
+	// SQLite allows only one writer at a time. WAL mode lets readers and a
+	// writer proceed concurrently, and busy_timeout makes database/sql's
+	// connection pool block and retry on SQLITE_BUSY instead of surfacing it
+	// as a request error under concurrent sends.
+	if _, err := db.Exec("PRAGMA journal_mode=WAL; PRAGMA busy_timeout=5000;"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to configure log store: %w", err)
+	}
+
+	const schema = `
+CREATE TABLE IF NOT EXISTS email_logs (
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	ts              TEXT NOT NULL,
+	sender          TEXT NOT NULL,
+	to_json         TEXT NOT NULL,
+	subject         TEXT NOT NULL,
+	body            TEXT NOT NULL,
+	ses_message_id  TEXT,
+	status          TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_email_logs_ts ON email_logs(ts);
+CREATE INDEX IF NOT EXISTS idx_email_logs_sender ON email_logs(sender);
+CREATE INDEX IF NOT EXISTS idx_email_logs_status ON email_logs(status);
+`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create log store schema: %w", err)
+	}
+
+	store := &sqliteLogStore{db: db}
+	if err := store.migrateLegacyJSON("email_logs.json"); err != nil {
+		logger.Warn("failed to import legacy email_logs.json", Fields{"error": err.Error()})
+	}
+
+	return store, nil
+}
+
+// migrateLegacyJSON imports the old JSON log file into SQLite the first time
+// the store starts up with an empty table.
+func (s *sqliteLogStore) migrateLegacyJSON(path string) error {
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM email_logs").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var legacy []Email
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+
+	for _, email := range legacy {
+		ts := email.Time
+		if ts == "" {
+			ts = time.Now().Format(time.RFC3339)
+		}
+		toJSON, err := json.Marshal(email.To)
+		if err != nil {
+			return err
+		}
+		if _, err := s.db.Exec(
+			`INSERT INTO email_logs (ts, sender, to_json, subject, body, ses_message_id, status) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			ts, email.From, string(toJSON), email.Subject, email.Body, "", "sent",
+		); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("imported legacy email log", Fields{"path": path, "count": len(legacy)})
+	return nil
+}
+
+func (s *sqliteLogStore) Insert(r LogRecord) (int64, error) {
+	toJSON, err := json.Marshal(r.To)
+	if err != nil {
+		return 0, err
+	}
+	if r.Timestamp.IsZero() {
+		r.Timestamp = time.Now()
+	}
+
+	result, err := s.db.Exec(
+		`INSERT INTO email_logs (ts, sender, to_json, subject, body, ses_message_id, status) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		r.Timestamp.Format(time.RFC3339), r.From, string(toJSON), r.Subject, r.Body, r.SESMessageID, r.Status,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (s *sqliteLogStore) Get(id int64) (*LogRecord, error) {
+	row := s.db.QueryRow(
+		`SELECT id, ts, sender, to_json, subject, body, ses_message_id, status FROM email_logs WHERE id = ?`, id,
+	)
+	record, err := scanLogRecord(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return record, err
+}
+
+// FindBySESMessageID looks up the log record for an outbound send by the SES
+// message ID, so an async bounce/complaint notification can be matched back
+// to it. Returns (nil, nil) if no record matches.
+func (s *sqliteLogStore) FindBySESMessageID(messageID string) (*LogRecord, error) {
+	row := s.db.QueryRow(
+		`SELECT id, ts, sender, to_json, subject, body, ses_message_id, status FROM email_logs WHERE ses_message_id = ? ORDER BY id DESC LIMIT 1`,
+		messageID,
+	)
+	record, err := scanLogRecord(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return record, err
+}
+
+func (s *sqliteLogStore) Query(q LogQuery) ([]LogRecord, int64, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultLogQueryLimit
+	}
+	if limit > maxLogQueryLimit {
+		limit = maxLogQueryLimit
+	}
+
+	var clauses []string
+	var args []interface{}
+
+	if q.From != "" {
+		clauses = append(clauses, "sender = ?")
+		args = append(args, q.From)
+	}
+	if q.To != "" {
+		clauses = append(clauses, "to_json LIKE ?")
+		args = append(args, "%"+q.To+"%")
+	}
+	if q.Status != "" {
+		clauses = append(clauses, "status = ?")
+		args = append(args, q.Status)
+	}
+	if !q.Since.IsZero() {
+		clauses = append(clauses, "ts >= ?")
+		args = append(args, q.Since.Format(time.RFC3339))
+	}
+	if !q.Until.IsZero() {
+		clauses = append(clauses, "ts <= ?")
+		args = append(args, q.Until.Format(time.RFC3339))
+	}
+	if q.Cursor > 0 {
+		clauses = append(clauses, "id > ?")
+		args = append(args, q.Cursor)
+	}
+
+	query := "SELECT id, ts, sender, to_json, subject, body, ses_message_id, status FROM email_logs"
+	if len(clauses) > 0 {
+		query += " WHERE " + strings.Join(clauses, " AND ")
+	}
+	query += " ORDER BY id ASC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var records []LogRecord
+	var lastID int64
+	for rows.Next() {
+		record, err := scanLogRecord(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		records = append(records, *record)
+		lastID = record.ID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	nextCursor := int64(0)
+	if len(records) == limit {
+		nextCursor = lastID
+	}
+	return records, nextCursor, nil
+}
+
+func (s *sqliteLogStore) UpdateStatus(id int64, status string) error {
+	result, err := s.db.Exec(`UPDATE email_logs SET status = ? WHERE id = ?`, status, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("no log record with id %d", id)
+	}
+	return nil
+}
+
+// ExportJSON writes every record as a JSON array, for backups.
+func (s *sqliteLogStore) ExportJSON(w io.Writer) error {
+	rows, err := s.db.Query(`SELECT id, ts, sender, to_json, subject, body, ses_message_id, status FROM email_logs ORDER BY id ASC`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var records []LogRecord
+	for rows.Next() {
+		record, err := scanLogRecord(rows)
+		if err != nil {
+			return err
+		}
+		records = append(records, *record)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+func (s *sqliteLogStore) Close() error {
+	return s.db.Close()
+}
+
+// rowScanner abstracts over *sql.Row and *sql.Rows so scanLogRecord can serve
+// both Get (single row) and Query (multiple rows).
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLogRecord(row rowScanner) (*LogRecord, error) {
+	var r LogRecord
+	var ts, toJSON string
+	if err := row.Scan(&r.ID, &ts, &r.From, &toJSON, &r.Subject, &r.Body, &r.SESMessageID, &r.Status); err != nil {
+		return nil, err
+	}
+
+	parsed, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stored timestamp %q: %w", ts, err)
+	}
+	r.Timestamp = parsed
+
+	if err := json.Unmarshal([]byte(toJSON), &r.To); err != nil {
+		return nil, fmt.Errorf("failed to parse stored recipients: %w", err)
+	}
+
+	return &r, nil
+}
+
+// parseLogQuery builds a LogQuery from GET /logs query parameters.
+func parseLogQuery(from, to, since, until, status, limit, cursor string) (LogQuery, error) {
+	q := LogQuery{From: from, To: to, Status: status}
+
+	if since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return q, fmt.Errorf("invalid since: %w", err)
+		}
+		q.Since = t
+	}
+	if until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return q, fmt.Errorf("invalid until: %w", err)
+		}
+		q.Until = t
+	}
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, fmt.Errorf("invalid limit: %w", err)
+		}
+		q.Limit = n
+	}
+	if cursor != "" {
+		n, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil {
+			return q, fmt.Errorf("invalid cursor: %w", err)
+		}
+		q.Cursor = n
+	}
+
+	return q, nil
+}
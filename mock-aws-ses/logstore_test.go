@@ -0,0 +1,154 @@
+package main
+
+import (
+	"testing"
+)
+
+func newTestLogStore(t *testing.T) *sqliteLogStore {
+	t.Helper()
+	store, err := NewSQLiteLogStore(t.TempDir() + "/logs.db")
+	if err != nil {
+		t.Fatalf("NewSQLiteLogStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestLogStoreInsertAndGet(t *testing.T) {
+	store := newTestLogStore(t)
+
+	id, err := store.Insert(LogRecord{From: "a@example.com", To: []string{"b@example.com"}, Status: "sent"})
+	if err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	record, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if record == nil || record.From != "a@example.com" || len(record.To) != 1 || record.To[0] != "b@example.com" {
+		t.Fatalf("Get(%d) = %+v, want the inserted record", id, record)
+	}
+}
+
+func TestLogStoreGetMissingReturnsNilNil(t *testing.T) {
+	store := newTestLogStore(t)
+
+	record, err := store.Get(999)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if record != nil {
+		t.Errorf("Get(999) = %+v, want nil for a missing record", record)
+	}
+}
+
+func TestLogStoreQueryPagination(t *testing.T) {
+	store := newTestLogStore(t)
+
+	for i := 0; i < 5; i++ {
+		if _, err := store.Insert(LogRecord{From: "a@example.com", To: []string{"b@example.com"}, Status: "sent"}); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+
+	page1, cursor, err := store.Query(LogQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(page1) != 2 || cursor == 0 {
+		t.Fatalf("first page = %d records, cursor %d; want 2 records with a next cursor", len(page1), cursor)
+	}
+
+	page2, cursor2, err := store.Query(LogQuery{Limit: 2, Cursor: cursor})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(page2) != 2 {
+		t.Fatalf("second page = %d records, want 2", len(page2))
+	}
+	if page1[0].ID == page2[0].ID {
+		t.Error("second page should not repeat records from the first page")
+	}
+
+	page3, cursor3, err := store.Query(LogQuery{Limit: 2, Cursor: cursor2})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(page3) != 1 {
+		t.Fatalf("final page = %d records, want 1 (5 total, 2 pages of 2 already consumed)", len(page3))
+	}
+	if cursor3 != 0 {
+		t.Error("the last page should not report a next cursor")
+	}
+}
+
+func TestLogStoreQueryFiltersByStatus(t *testing.T) {
+	store := newTestLogStore(t)
+	store.Insert(LogRecord{From: "a@example.com", To: []string{"b@example.com"}, Status: "sent"})
+	store.Insert(LogRecord{From: "a@example.com", To: []string{"b@example.com"}, Status: "bounced"})
+
+	records, _, err := store.Query(LogQuery{Status: "bounced"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(records) != 1 || records[0].Status != "bounced" {
+		t.Fatalf("Query(Status=bounced) = %+v, want exactly the bounced record", records)
+	}
+}
+
+func TestLogStoreUpdateStatus(t *testing.T) {
+	store := newTestLogStore(t)
+	id, _ := store.Insert(LogRecord{From: "a@example.com", To: []string{"b@example.com"}, Status: "sent"})
+
+	if err := store.UpdateStatus(id, "bounced"); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	record, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if record.Status != "bounced" {
+		t.Errorf("Status = %q, want bounced", record.Status)
+	}
+
+	if err := store.UpdateStatus(999, "bounced"); err == nil {
+		t.Error("UpdateStatus on a missing id should return an error")
+	}
+}
+
+func TestLogStoreFindBySESMessageID(t *testing.T) {
+	store := newTestLogStore(t)
+	id, _ := store.Insert(LogRecord{From: "a@example.com", To: []string{"b@example.com"}, SESMessageID: "msg-123", Status: "sent"})
+
+	record, err := store.FindBySESMessageID("msg-123")
+	if err != nil {
+		t.Fatalf("FindBySESMessageID: %v", err)
+	}
+	if record == nil || record.ID != id {
+		t.Fatalf("FindBySESMessageID(msg-123) = %+v, want the inserted record", record)
+	}
+
+	missing, err := store.FindBySESMessageID("no-such-id")
+	if err != nil {
+		t.Fatalf("FindBySESMessageID: %v", err)
+	}
+	if missing != nil {
+		t.Errorf("FindBySESMessageID(no-such-id) = %+v, want nil", missing)
+	}
+}
+
+func TestParseLogQuery(t *testing.T) {
+	q, err := parseLogQuery("a@example.com", "", "2024-01-01T00:00:00Z", "", "sent", "10", "5")
+	if err != nil {
+		t.Fatalf("parseLogQuery: %v", err)
+	}
+	if q.From != "a@example.com" || q.Status != "sent" || q.Limit != 10 || q.Cursor != 5 {
+		t.Errorf("parseLogQuery = %+v, unexpected field values", q)
+	}
+
+	if _, err := parseLogQuery("", "", "not-a-time", "", "", "", ""); err == nil {
+		t.Error("parseLogQuery should reject an invalid since timestamp")
+	}
+}
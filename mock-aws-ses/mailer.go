@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ses"
+)
+
+// Mailer sends a single Email through some outbound transport. The Gin
+// handler and the inbound SMTP bridge only ever talk to this interface, so
+// neither knows (or needs to know) which backend is active. The returned
+// message ID is backend-specific (e.g. the SES message ID) and may be empty
+// for backends that don't provide one.
+type Mailer interface {
+	Send(email Email) (messageID string, err error)
+}
+
+// newMailer selects the outbound Mailer backend based on cfg.MailerBackend.
+func newMailer(cfg *Config) Mailer {
+	switch cfg.MailerBackend {
+	case "smtp":
+		return &smtpMailer{
+			addr: cfg.OutboundSMTPAddr,
+			user: cfg.OutboundSMTPUser,
+			pass: cfg.OutboundSMTPPass,
+			from: cfg.OutboundSMTPFrom,
+		}
+	default:
+		return &sesMailer{}
+	}
+}
+
+// isThrottlingError reports whether err is an SES throttling response, which
+// should land the sender in the penalty box rather than a plain 500.
+func isThrottlingError(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case "Throttling", "ThrottlingException", "TooManyRequestsException":
+			return true
+		}
+	}
+	return false
+}
+
+// sesMailer sends email via AWS SES.
+type sesMailer struct{}
+
+func (m *sesMailer) Send(email Email) (string, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String("us-east-1"), // Change to your AWS region
+	})
+	if err != nil {
+		return "", err
+	}
+
+	svc := ses.New(sess)
+
+	// Convert recipient list to AWS SES format
+	toAddresses := make([]*string, len(email.To))
+	for i, recipient := range email.To {
+		toAddresses[i] = aws.String(recipient)
+	}
+
+	// Email input
+	input := &ses.SendEmailInput{
+		Destination: &ses.Destination{
+			ToAddresses: toAddresses,
+		},
+		Message: &ses.Message{
+			Body: &ses.Body{
+				Text: &ses.Content{
+					Data: aws.String(email.Body),
+				},
+			},
+			Subject: &ses.Content{
+				Data: aws.String(email.Subject),
+			},
+		},
+		Source: aws.String(email.From),
+	}
+
+	// Send the email
+	output, err := svc.SendEmail(input)
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(output.MessageId), nil
+}
+
+// smtpMailer relays email through an outbound SMTP server, with STARTTLS and
+// PLAIN/LOGIN auth.
+type smtpMailer struct {
+	addr string
+	user string
+	pass string
+	from string
+}
+
+func (m *smtpMailer) Send(email Email) (string, error) {
+	if m.addr == "" {
+		return "", fmt.Errorf("smtp mailer: --smtp-addr is not configured")
+	}
+
+	host, _, err := net.SplitHostPort(m.addr)
+	if err != nil {
+		return "", fmt.Errorf("smtp mailer: %w", err)
+	}
+
+	c, err := smtp.Dial(m.addr)
+	if err != nil {
+		return "", fmt.Errorf("smtp mailer: dial failed: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return "", fmt.Errorf("smtp mailer: starttls failed: %w", err)
+		}
+	}
+
+	if m.user != "" {
+		if ok, _ := c.Extension("AUTH"); ok {
+			auth := smtp.PlainAuth("", m.user, m.pass, host)
+			if err := c.Auth(auth); err != nil {
+				return "", fmt.Errorf("smtp mailer: auth failed: %w", err)
+			}
+		}
+	}
+
+	from := email.From
+	if m.from != "" {
+		from = m.from
+	}
+
+	if err := c.Mail(from); err != nil {
+		return "", fmt.Errorf("smtp mailer: MAIL FROM failed: %w", err)
+	}
+	for _, recipient := range email.To {
+		if err := c.Rcpt(recipient); err != nil {
+			return "", fmt.Errorf("smtp mailer: RCPT TO failed for %s: %w", recipient, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return "", fmt.Errorf("smtp mailer: DATA failed: %w", err)
+	}
+	if _, err := w.Write(buildRFC822Message(email)); err != nil {
+		return "", fmt.Errorf("smtp mailer: writing message failed: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("smtp mailer: closing message failed: %w", err)
+	}
+
+	return "", c.Quit()
+}
+
+func buildRFC822Message(email Email) []byte {
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n",
+		email.From, joinAddresses(email.To), email.Subject, email.Body)
+	return []byte(msg)
+}
+
+func joinAddresses(addrs []string) string {
+	out := ""
+	for i, a := range addrs {
+		if i > 0 {
+			out += ", "
+		}
+		out += a
+	}
+	return out
+}
+
+// testMailer is a fake Mailer that records sent messages in memory instead
+// of delivering them, for use in unit tests.
+type testMailer struct {
+	mu   sync.Mutex
+	sent []Email
+}
+
+func (m *testMailer) Send(email Email) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, email)
+	return fmt.Sprintf("test-%d", len(m.sent)), nil
+}
+
+func (m *testMailer) Sent() []Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Email, len(m.sent))
+	copy(out, m.sent)
+	return out
+}
@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestTestMailerRecordsSentMessages(t *testing.T) {
+	m := &testMailer{}
+
+	id1, err := m.Send(Email{From: "a@example.com", To: []string{"b@example.com"}})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if _, err := m.Send(Email{From: "c@example.com", To: []string{"d@example.com"}}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	sent := m.Sent()
+	if len(sent) != 2 {
+		t.Fatalf("Sent() = %d messages, want 2", len(sent))
+	}
+	if sent[0].From != "a@example.com" {
+		t.Errorf("sent[0].From = %q, want a@example.com", sent[0].From)
+	}
+	if id1 == "" {
+		t.Error("Send returned an empty message ID")
+	}
+}
+
+func TestNewMailerSelectsBackendFromConfig(t *testing.T) {
+	if _, ok := newMailer(&Config{MailerBackend: "ses"}).(*sesMailer); !ok {
+		t.Error("MailerBackend \"ses\" did not select sesMailer")
+	}
+	if _, ok := newMailer(&Config{MailerBackend: "smtp"}).(*smtpMailer); !ok {
+		t.Error("MailerBackend \"smtp\" did not select smtpMailer")
+	}
+	if _, ok := newMailer(&Config{MailerBackend: ""}).(*sesMailer); !ok {
+		t.Error("empty MailerBackend did not default to sesMailer")
+	}
+}
+
+func TestSMTPMailerRequiresAddr(t *testing.T) {
+	m := &smtpMailer{}
+	if _, err := m.Send(Email{From: "a@example.com", To: []string{"b@example.com"}}); err == nil {
+		t.Error("Send with no --smtp-addr configured should fail")
+	}
+}
+
+func TestIsThrottlingError(t *testing.T) {
+	if isThrottlingError(nil) {
+		t.Error("nil error should not be treated as throttling")
+	}
+	if isThrottlingError(errString("boom")) {
+		t.Error("a plain error should not be treated as throttling")
+	}
+}
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
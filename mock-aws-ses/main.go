@@ -1,17 +1,17 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/ses"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
@@ -25,88 +25,326 @@ type Email struct {
 	Time    string   `json:"time"`
 }
 
-var emailLogs []Email
-var emailCount = 0
 var startTime = time.Now()
 
-const maxEmailsPerHour = 5 // Change limit if needed
+// Config holds the tunables for the visitor quota subsystem. Every field can
+// be set via an env var (upper-cased, dashes -> underscores) or overridden by
+// the matching CLI flag.
+type Config struct {
+	VisitorEmailLimitBurst     int
+	VisitorEmailLimitReplenish time.Duration
+	VisitorMessageDailyLimit   int
+	PenaltyDuration            time.Duration
+	VisitorIdleTimeout         time.Duration
+
+	SMTPListen                 string
+	SMTPAllowedRecipientDomain string
+	SMTPMaxMessageBytes        int64
+	SMTPAnonymousLogin         bool
+
+	MailerBackend    string // "ses" or "smtp"
+	OutboundSMTPAddr string
+	OutboundSMTPUser string
+	OutboundSMTPPass string
+	OutboundSMTPFrom string
+
+	LogLevel   string
+	ConfigFile string
+
+	DBPath string
+
+	BounceComplaintThreshold int
+	BounceComplaintWindow    time.Duration
+}
 
-// Rate Limiter per IP
-type RateLimiter struct {
-	visitors map[string]*rate.Limiter
-	mu       sync.Mutex
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+func envBool(key string, def bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
 }
 
-// Initialize rate limiter
-func NewRateLimiter() *RateLimiter {
-	return &RateLimiter{
-		visitors: make(map[string]*rate.Limiter),
+func envDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
 	}
+	return def
 }
 
-// Get limiter for an IP address
-func (r *RateLimiter) GetLimiter(ip string) *rate.Limiter {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func envKey(flagName string) string {
+	return strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
 
-	// If IP exists, return existing limiter
-	if limiter, exists := r.visitors[ip]; exists {
-		return limiter
+// loadConfig reads defaults from the environment and lets CLI flags override
+// them, following the flag-with-env-default pattern.
+func loadConfig() *Config {
+	cfg := &Config{}
+
+	burst := flag.Int("visitor-email-limit-burst", envInt(envKey("visitor-email-limit-burst"), 5),
+		"max emails a single sender+IP may send in a burst before it must wait for replenishment")
+	replenish := flag.Duration("visitor-email-limit-replenish", envDuration(envKey("visitor-email-limit-replenish"), time.Minute),
+		"how often a sender+IP regains one burst slot")
+	dailyLimit := flag.Int("visitor-message-daily-limit", envInt(envKey("visitor-message-daily-limit"), 50),
+		"max emails a single sender+IP may send in a rolling 24h window")
+	penalty := flag.Duration("email-penalty-duration", envDuration(envKey("email-penalty-duration"), 10*time.Minute),
+		"how long a sender is blocked after a bounce, complaint, or SES throttling error")
+	idleTimeout := flag.Duration("visitor-idle-timeout", envDuration(envKey("visitor-idle-timeout"), 24*time.Hour),
+		"how long a visitor can sit idle before its entry is pruned")
+	smtpListen := flag.String("smtp-listen", envString(envKey("smtp-listen"), ""),
+		"address to listen on for inbound SMTP (e.g. :2525); disabled if empty")
+	smtpRecipientDomain := flag.String("smtp-allowed-recipient-domain", envString(envKey("smtp-allowed-recipient-domain"), ""),
+		"if set, inbound SMTP only accepts recipients in this domain")
+	smtpMaxMessageBytes := flag.Int64("smtp-max-message-bytes", int64(envInt(envKey("smtp-max-message-bytes"), 5*1024*1024)),
+		"max accepted size of an inbound SMTP message, in bytes")
+	smtpAnonymous := flag.Bool("smtp-anonymous-login", envBool(envKey("smtp-anonymous-login"), true),
+		"allow inbound SMTP clients to authenticate anonymously")
+	mailerBackend := flag.String("mailer", envString(envKey("mailer"), "ses"),
+		"outbound mailer backend to use: ses or smtp")
+	outboundSMTPAddr := flag.String("smtp-addr", envString(envKey("smtp-addr"), ""),
+		"host:port of the outbound SMTP relay (used when --mailer=smtp)")
+	outboundSMTPUser := flag.String("smtp-user", envString(envKey("smtp-user"), ""),
+		"username for the outbound SMTP relay")
+	outboundSMTPPass := flag.String("smtp-pass", envString(envKey("smtp-pass"), ""),
+		"password for the outbound SMTP relay")
+	outboundSMTPFrom := flag.String("smtp-from", envString(envKey("smtp-from"), ""),
+		"envelope sender to use when relaying through the outbound SMTP backend")
+	logLevel := flag.String("log-level", envString(envKey("log-level"), "INFO"),
+		"log level: TRACE, DEBUG, INFO, WARN, or ERROR")
+	configFile := flag.String("config-file", envString(envKey("config-file"), ""),
+		"path to a JSON config file that can override the log level; reloaded on SIGHUP")
+	dbPath := flag.String("db-path", envString(envKey("db-path"), "email_logs.db"),
+		"path to the SQLite database used to store delivered email logs")
+	bounceThreshold := flag.Int("bounce-complaint-threshold", envInt(envKey("bounce-complaint-threshold"), 3),
+		"number of bounces/complaints within the window that penalty-boxes a sender")
+	bounceWindow := flag.Duration("bounce-complaint-window", envDuration(envKey("bounce-complaint-window"), time.Hour),
+		"rolling window over which bounces/complaints are counted")
+
+	if !flag.Parsed() {
+		flag.Parse()
 	}
 
-	// Otherwise, create a new limiter (e.g., 5 requests per minute)
-	limiter := rate.NewLimiter(5, 5)
-	r.visitors[ip] = limiter
-	return limiter
+	cfg.VisitorEmailLimitBurst = *burst
+	cfg.VisitorEmailLimitReplenish = *replenish
+	cfg.VisitorMessageDailyLimit = *dailyLimit
+	cfg.PenaltyDuration = *penalty
+	cfg.VisitorIdleTimeout = *idleTimeout
+	cfg.SMTPListen = *smtpListen
+	cfg.SMTPAllowedRecipientDomain = *smtpRecipientDomain
+	cfg.SMTPMaxMessageBytes = *smtpMaxMessageBytes
+	cfg.SMTPAnonymousLogin = *smtpAnonymous
+	cfg.MailerBackend = *mailerBackend
+	cfg.OutboundSMTPAddr = *outboundSMTPAddr
+	cfg.OutboundSMTPUser = *outboundSMTPUser
+	cfg.OutboundSMTPPass = *outboundSMTPPass
+	cfg.OutboundSMTPFrom = *outboundSMTPFrom
+	cfg.LogLevel = *logLevel
+	cfg.ConfigFile = *configFile
+	cfg.DBPath = *dbPath
+	cfg.BounceComplaintThreshold = *bounceThreshold
+	cfg.BounceComplaintWindow = *bounceWindow
+
+	return cfg
 }
 
-// Middleware for rate limiting
-func rateLimitMiddleware(rl *RateLimiter) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		limiter := rl.GetLimiter(ip)
+// Visitor tracks rate-limiting state for a single sender+IP pair: a token
+// bucket for short-burst pacing, a rolling 24h message counter, and an
+// optional penalty-box expiry.
+type Visitor struct {
+	limiter         *rate.Limiter
+	dailyCount      int
+	dailyWindowFrom time.Time
+	penalizedUntil  time.Time
+	penaltyReason   string
+	lastSeen        time.Time
+}
 
-		// If user exceeds rate limit, block the request
-		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests. Please try again later."})
-			c.Abort()
-			return
-		}
-		c.Next()
+// VisitorStats is the per-sender snapshot returned by GET /stats.
+type VisitorStats struct {
+	DailyCount     int       `json:"daily_count"`
+	DailyRemaining int       `json:"daily_remaining"`
+	PenaltyExpiry  time.Time `json:"penalty_expiry,omitempty"`
+	PenaltyReason  string    `json:"penalty_reason,omitempty"`
+}
+
+// senderPenalty blocks a sender address across every client IP, independent
+// of the per-(sender,IP) penalty box above. It's used for bounce/complaint
+// driven blocks, since SES notifications carry no client IP to key on.
+type senderPenalty struct {
+	until  time.Time
+	reason string
+}
+
+// VisitorStore keeps per-sender+IP quota state and evicts idle entries so the
+// map doesn't grow without bound.
+type VisitorStore struct {
+	mu              sync.Mutex
+	visitors        map[string]*Visitor
+	senderPenalties map[string]senderPenalty
+	cfg             *Config
+}
+
+func NewVisitorStore(cfg *Config) *VisitorStore {
+	s := &VisitorStore{
+		visitors:        make(map[string]*Visitor),
+		senderPenalties: make(map[string]senderPenalty),
+		cfg:             cfg,
 	}
+	go s.prunerLoop()
+	return s
 }
 
-// Save email log
-func saveEmailLog(email Email) error {
-	logs, err := loadEmailLogs()
-	if err != nil {
-		return err
+func visitorKey(from, ip string) string {
+	return from + "|" + ip
+}
+
+// getOrCreate returns the visitor for key, creating it if needed. Caller must
+// hold s.mu.
+func (s *VisitorStore) getOrCreate(key string) *Visitor {
+	if v, ok := s.visitors[key]; ok {
+		return v
+	}
+	v := &Visitor{
+		limiter:         rate.NewLimiter(rate.Every(s.cfg.VisitorEmailLimitReplenish), s.cfg.VisitorEmailLimitBurst),
+		dailyWindowFrom: time.Now(),
 	}
+	s.visitors[key] = v
+	return v
+}
 
-	logs = append(logs, email)
+// Allow reports whether from/ip may send another email right now. On
+// rejection it also returns a human-readable reason.
+func (s *VisitorStore) Allow(from, ip string) (bool, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	file, err := json.MarshalIndent(logs, "", "  ")
-	if err != nil {
-		return err
+	if p, ok := s.senderPenalties[from]; ok && time.Now().Before(p.until) {
+		return false, fmt.Sprintf("sender is in the penalty box until %s (%s)", p.until.Format(time.RFC3339), p.reason)
 	}
 
-	return os.WriteFile("email_logs.json", file, 0644)
+	key := visitorKey(from, ip)
+	v := s.getOrCreate(key)
+	v.lastSeen = time.Now()
+
+	if time.Now().Before(v.penalizedUntil) {
+		return false, fmt.Sprintf("sender is in the penalty box until %s (%s)", v.penalizedUntil.Format(time.RFC3339), v.penaltyReason)
+	}
+
+	if time.Since(v.dailyWindowFrom) >= 24*time.Hour {
+		v.dailyWindowFrom = time.Now()
+		v.dailyCount = 0
+	}
+
+	if v.dailyCount >= s.cfg.VisitorMessageDailyLimit {
+		return false, "daily message limit exceeded"
+	}
+
+	if !v.limiter.Allow() {
+		return false, "too many emails in a short period, please slow down"
+	}
+
+	v.dailyCount++
+	return true, ""
 }
 
-// Load email logs
-func loadEmailLogs() ([]Email, error) {
-	file, err := os.ReadFile("email_logs.json")
-	if err != nil {
-		return []Email{}, nil
+// Penalize puts from/ip in the penalty box for the configured duration,
+// e.g. after an SES throttling error.
+func (s *VisitorStore) Penalize(from, ip, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := visitorKey(from, ip)
+	v := s.getOrCreate(key)
+	v.penalizedUntil = time.Now().Add(s.cfg.PenaltyDuration)
+	v.penaltyReason = reason
+}
+
+// PenalizeSender blocks every IP for the given sender address, e.g. after
+// its bounce/complaint rate crosses the configured threshold.
+func (s *VisitorStore) PenalizeSender(from, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.senderPenalties[from] = senderPenalty{
+		until:  time.Now().Add(s.cfg.PenaltyDuration),
+		reason: reason,
 	}
+}
 
-	var logs []Email
-	err = json.Unmarshal(file, &logs)
-	if err != nil {
-		return []Email{}, err
+// ClearSenderPenalty lifts a sender-level penalty early, e.g. via
+// DELETE /admin/penalty/{sender}. It reports whether a penalty existed.
+func (s *VisitorStore) ClearSenderPenalty(from string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, existed := s.senderPenalties[from]
+	delete(s.senderPenalties, from)
+	return existed
+}
+
+// Stats returns a snapshot of every known visitor, keyed by "from|ip".
+func (s *VisitorStore) Stats() map[string]VisitorStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]VisitorStats, len(s.visitors))
+	for key, v := range s.visitors {
+		remaining := s.cfg.VisitorMessageDailyLimit - v.dailyCount
+		if remaining < 0 {
+			remaining = 0
+		}
+		stats := VisitorStats{
+			DailyCount:     v.dailyCount,
+			DailyRemaining: remaining,
+		}
+		if time.Now().Before(v.penalizedUntil) {
+			stats.PenaltyExpiry = v.penalizedUntil
+			stats.PenaltyReason = v.penaltyReason
+		}
+		out[key] = stats
+	}
+	return out
+}
+
+// prune evicts visitors that haven't been seen in longer than idleTimeout.
+func (s *VisitorStore) prune(idleTimeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, v := range s.visitors {
+		if now.Sub(v.lastSeen) > idleTimeout {
+			delete(s.visitors, key)
+		}
 	}
+}
 
-	return logs, nil
+// prunerLoop periodically evicts idle visitors so memory stays bounded.
+func (s *VisitorStore) prunerLoop() {
+	ticker := time.NewTicker(s.cfg.VisitorIdleTimeout / 4)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.prune(s.cfg.VisitorIdleTimeout)
+	}
 }
 
 // Validate email format
@@ -116,62 +354,140 @@ func isValidEmail(email string) bool {
 	return re.MatchString(email)
 }
 
-// Send email using AWS SES
-func sendEmailWithSES(email Email) error {
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-east-1"), // Change to your AWS region
-	})
+// Server wires together the quota subsystem and delivery path shared by the
+// HTTP API and the inbound SMTP bridge, so neither entry point duplicates
+// validation, quota, or send logic.
+type Server struct {
+	cfg      *Config
+	visitors *VisitorStore
+
+	smtpMu      sync.Mutex
+	smtpSuccess int
+	smtpFailure int
+
+	mailer        Mailer
+	logStore      LogStore
+	bounceTracker *BounceTracker
+}
+
+func NewServer(cfg *Config) *Server {
+	logStore, err := NewSQLiteLogStore(cfg.DBPath)
 	if err != nil {
-		return err
+		logger.Error("failed to open log store", Fields{"db_path": cfg.DBPath, "error": err.Error()})
+		os.Exit(1)
+	}
+
+	return &Server{
+		cfg:           cfg,
+		visitors:      NewVisitorStore(cfg),
+		mailer:        newMailer(cfg),
+		logStore:      logStore,
+		bounceTracker: NewBounceTracker(cfg),
+	}
+}
+
+// deliver validates, quota-checks, sends, and logs email on behalf of the
+// given client IP. It is used by both the HTTP /send-email handler and the
+// inbound SMTP backend. requestID correlates the structured log lines it
+// emits with the entry point that called it.
+func (s *Server) deliver(requestID string, email Email, ip string) error {
+	start := time.Now()
+	fields := Fields{
+		"request_id":      requestID,
+		"client_ip":       ip,
+		"sender":          email.From,
+		"recipient_count": len(email.To),
+	}
+
+	if !isValidEmail(email.From) {
+		logger.Warn("rejected email: invalid sender", fields)
+		return fmt.Errorf("invalid sender email format")
+	}
+	for _, recipient := range email.To {
+		if !isValidEmail(recipient) {
+			logger.Warn("rejected email: invalid recipient", fields)
+			return fmt.Errorf("invalid recipient email format")
+		}
 	}
 
-	svc := ses.New(sess)
+	if allowed, reason := s.visitors.Allow(email.From, ip); !allowed {
+		logger.Warn("rejected email: quota exceeded", fields.with("reason", reason))
+		return fmt.Errorf("%s", reason)
+	}
 
-	// Convert recipient list to AWS SES format
-	toAddresses := make([]*string, len(email.To))
-	for i, recipient := range email.To {
-		toAddresses[i] = aws.String(recipient)
+	messageID, err := s.mailer.Send(email)
+	if err != nil {
+		if isThrottlingError(err) {
+			s.visitors.Penalize(email.From, ip, "SES throttling")
+		}
+		logger.Error("failed to send email", fields.with("error", err.Error()).with("latency_ms", time.Since(start).Milliseconds()))
+		return fmt.Errorf("failed to send email")
 	}
 
-	// Email input
-	input := &ses.SendEmailInput{
-		Destination: &ses.Destination{
-			ToAddresses: toAddresses,
-		},
-		Message: &ses.Message{
-			Body: &ses.Body{
-				Text: &ses.Content{
-					Data: aws.String(email.Body),
-				},
-			},
-			Subject: &ses.Content{
-				Data: aws.String(email.Subject),
-			},
-		},
-		Source: aws.String(email.From),
+	email.Time = time.Now().Format(time.RFC3339)
+	record := LogRecord{
+		Timestamp:    time.Now(),
+		From:         email.From,
+		To:           email.To,
+		Subject:      email.Subject,
+		Body:         email.Body,
+		SESMessageID: messageID,
+		Status:       "sent",
+	}
+	if _, err := s.logStore.Insert(record); err != nil {
+		logger.Error("failed to save email log", fields.with("error", err.Error()))
+		return fmt.Errorf("failed to save email log: %w", err)
+	}
+
+	logger.Info("email sent", fields.with("ses_message_id", messageID).with("latency_ms", time.Since(start).Milliseconds()))
+	return nil
+}
+
+// recordSMTPResult tracks inbound SMTP delivery outcomes for GET /stats.
+func (s *Server) recordSMTPResult(err error) {
+	s.smtpMu.Lock()
+	defer s.smtpMu.Unlock()
+	if err != nil {
+		s.smtpFailure++
+	} else {
+		s.smtpSuccess++
 	}
+}
 
-	// Send the email
-	_, err = svc.SendEmail(input)
-	return err
+func (s *Server) smtpStats() gin.H {
+	s.smtpMu.Lock()
+	defer s.smtpMu.Unlock()
+	return gin.H{"success": s.smtpSuccess, "failure": s.smtpFailure}
 }
 
 func main() {
-	// Initialize rate limiter
-	rateLimiter := NewRateLimiter()
+	cfg := loadConfig()
+	server := NewServer(cfg)
+
+	if level, err := ParseLogLevel(cfg.LogLevel); err == nil {
+		logger.SetLevel(level)
+	}
+	if level, ok := loadLogLevelFromFile(cfg.ConfigFile); ok {
+		logger.SetLevel(level)
+	}
+	watchSIGHUP(cfg)
+
+	if cfg.SMTPListen != "" {
+		go func() {
+			if err := runSMTPServer(server); err != nil {
+				fmt.Fprintf(os.Stderr, "smtp server stopped: %v\n", err)
+			}
+		}()
+	}
 
-	// Initialize Gin router
-	r := gin.Default()
-	r.Use(rateLimitMiddleware(rateLimiter)) // Apply rate limiting
+	// Initialize Gin router. gin.Default()'s built-in Logger() middleware is
+	// intentionally left out: request logging goes through the structured
+	// logger instead, not a second plaintext access log.
+	r := gin.New()
+	r.Use(gin.Recovery())
 
 	// Email sending route
 	r.POST("/send-email", func(c *gin.Context) {
-		// Reset email count if 1 hour has passed
-		if time.Since(startTime).Hours() >= 1 {
-			startTime = time.Now()
-			emailCount = 0
-		}
-
 		// Parse JSON request
 		var email Email
 		if err := c.ShouldBindJSON(&email); err != nil {
@@ -179,60 +495,128 @@ func main() {
 			return
 		}
 
-		// Validate email addresses
-		if !isValidEmail(email.From) {
-			c.JSON(400, gin.H{"error": "Invalid sender email format."})
+		if err := server.deliver(newRequestID(), email, c.ClientIP()); err != nil {
+			c.JSON(statusForDeliverError(err), gin.H{"error": err.Error()})
 			return
 		}
-		for _, recipient := range email.To {
-			if !isValidEmail(recipient) {
-				c.JSON(400, gin.H{"error": "Invalid recipient email format."})
-				return
-			}
+
+		c.JSON(200, gin.H{"message": "Email sent successfully"})
+	})
+
+	// API usage stats
+	r.GET("/stats", func(c *gin.Context) {
+		elapsedTime := time.Since(startTime).Hours()
+
+		c.JSON(200, gin.H{
+			"time_elapsed_since_start": fmt.Sprintf("%.2f hours", elapsedTime),
+			"visitors":                 server.visitors.Stats(),
+			"smtp":                     server.smtpStats(),
+		})
+	})
+
+	// Hot-reload the log level without restarting the server
+	r.POST("/admin/log-level", func(c *gin.Context) {
+		var body struct {
+			Level string `json:"level"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request body. Expected {\"level\": \"DEBUG\"}."})
+			return
 		}
 
-		// Check email sending limit
-		if emailCount >= maxEmailsPerHour {
-			c.JSON(429, gin.H{"error": "Email limit exceeded. Try again later."})
+		level, err := ParseLogLevel(body.Level)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
 
-		// Send email using AWS SES
-		err := sendEmailWithSES(email)
+		logger.SetLevel(level)
+		logger.Info("log level changed via /admin/log-level", Fields{"level": level.String()})
+		c.JSON(200, gin.H{"level": level.String()})
+	})
+
+	// Paginated log query API, backed by the SQLite log store
+	r.GET("/logs", func(c *gin.Context) {
+		q, err := parseLogQuery(
+			c.Query("from"), c.Query("to"), c.Query("since"), c.Query("until"),
+			c.Query("status"), c.Query("limit"), c.Query("cursor"),
+		)
 		if err != nil {
-			c.JSON(500, gin.H{"error": "Failed to send email via AWS SES."})
+			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
 
-		// Log the email
-		email.Time = time.Now().Format(time.RFC3339)
-		err = saveEmailLog(email)
+		records, nextCursor, err := server.logStore.Query(q)
 		if err != nil {
-			c.JSON(500, gin.H{"error": "Failed to save email log."})
+			logger.Error("failed to query logs", Fields{"error": err.Error()})
+			c.JSON(500, gin.H{"error": "Failed to query logs."})
 			return
 		}
 
-		// Increment email count
-		emailCount++
+		resp := gin.H{"logs": records}
+		if nextCursor > 0 {
+			resp["next_cursor"] = nextCursor
+		}
+		c.JSON(200, resp)
+	})
 
-		c.JSON(200, gin.H{"message": "Email sent successfully via AWS SES", "emails_sent": emailCount})
+	// Single log record by ID
+	r.GET("/logs/:id", func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid log id."})
+			return
+		}
+
+		record, err := server.logStore.Get(id)
+		if err != nil {
+			logger.Error("failed to fetch log record", Fields{"id": id, "error": err.Error()})
+			c.JSON(500, gin.H{"error": "Failed to fetch log record."})
+			return
+		}
+		if record == nil {
+			c.JSON(404, gin.H{"error": "Log record not found."})
+			return
+		}
+
+		c.JSON(200, record)
 	})
 
-	// API usage stats
-	r.GET("/stats", func(c *gin.Context) {
-		elapsedTime := time.Since(startTime).Hours()
-		remainingEmails := maxEmailsPerHour - emailCount
-		if remainingEmails < 0 {
-			remainingEmails = 0
+	// AWS SNS delivery of SES bounce/complaint/delivery notifications
+	r.POST("/ses/notifications", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Failed to read request body."})
+			return
 		}
 
-		c.JSON(200, gin.H{
-			"total_emails_sent":             emailCount,
-			"emails_sent_last_hour":         emailCount,
-			"time_elapsed_since_reset":      fmt.Sprintf("%.2f hours", elapsedTime),
-			"remaining_emails_before_limit": remainingEmails,
-			"limit_reached":                 emailCount >= maxEmailsPerHour,
-		})
+		if err := server.handleSESNotification(body); err != nil {
+			logger.Warn("failed to process SES notification", Fields{"error": err.Error()})
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, gin.H{"message": "Notification processed."})
+	})
+
+	// Manually clear a sender's bounce/complaint penalty box
+	r.DELETE("/admin/penalty/:sender", func(c *gin.Context) {
+		sender := c.Param("sender")
+		if server.visitors.ClearSenderPenalty(sender) {
+			logger.Info("cleared sender penalty box", Fields{"sender": sender})
+			c.JSON(200, gin.H{"message": "Penalty cleared."})
+			return
+		}
+		c.JSON(404, gin.H{"error": "Sender is not in the penalty box."})
+	})
+
+	// JSON backup export of every log record
+	r.GET("/admin/log-export", func(c *gin.Context) {
+		c.Header("Content-Disposition", "attachment; filename=email_logs_backup.json")
+		c.Status(200)
+		if err := server.logStore.ExportJSON(c.Writer); err != nil {
+			logger.Error("failed to export log backup", Fields{"error": err.Error()})
+		}
 	})
 
 	// Test endpoint
@@ -243,3 +627,18 @@ func main() {
 	// Start the server
 	r.Run(":8080")
 }
+
+// statusForDeliverError maps a deliver() error to an HTTP status code so the
+// HTTP handler can keep returning the same status codes as before the
+// HTTP/SMTP paths were unified.
+func statusForDeliverError(err error) int {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "invalid sender") || strings.Contains(msg, "invalid recipient"):
+		return http.StatusBadRequest
+	case strings.Contains(msg, "penalty box") || strings.Contains(msg, "limit exceeded") || strings.Contains(msg, "slow down"):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
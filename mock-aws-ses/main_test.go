@@ -0,0 +1,200 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func testConfig() *Config {
+	return &Config{
+		VisitorEmailLimitBurst:     2,
+		VisitorEmailLimitReplenish: time.Hour,
+		VisitorMessageDailyLimit:   3,
+		PenaltyDuration:            10 * time.Minute,
+		VisitorIdleTimeout:         24 * time.Hour,
+		BounceComplaintThreshold:   3,
+		BounceComplaintWindow:      time.Hour,
+	}
+}
+
+func TestVisitorStoreAllowEnforcesBurstLimit(t *testing.T) {
+	store := NewVisitorStore(testConfig())
+
+	for i := 0; i < 2; i++ {
+		if allowed, reason := store.Allow("a@example.com", "1.1.1.1"); !allowed {
+			t.Fatalf("send %d: want allowed, got denied (%s)", i, reason)
+		}
+	}
+
+	if allowed, reason := store.Allow("a@example.com", "1.1.1.1"); allowed {
+		t.Fatal("3rd send within the burst window should be denied")
+	} else if reason == "" {
+		t.Error("denied send should carry a reason")
+	}
+}
+
+func TestVisitorStoreAllowEnforcesDailyLimit(t *testing.T) {
+	cfg := testConfig()
+	cfg.VisitorEmailLimitBurst = 100
+	store := NewVisitorStore(cfg)
+
+	for i := 0; i < cfg.VisitorMessageDailyLimit; i++ {
+		if allowed, reason := store.Allow("a@example.com", "1.1.1.1"); !allowed {
+			t.Fatalf("send %d: want allowed, got denied (%s)", i, reason)
+		}
+	}
+
+	if allowed, _ := store.Allow("a@example.com", "1.1.1.1"); allowed {
+		t.Fatal("send beyond the daily limit should be denied")
+	}
+
+	// A different sender+IP pair has its own independent quota.
+	if allowed, reason := store.Allow("b@example.com", "1.1.1.1"); !allowed {
+		t.Fatalf("different sender should not share a@example.com's quota: %s", reason)
+	}
+}
+
+func TestVisitorStorePenalizeBlocksSender(t *testing.T) {
+	store := NewVisitorStore(testConfig())
+
+	store.Penalize("a@example.com", "1.1.1.1", "SES throttling")
+
+	allowed, reason := store.Allow("a@example.com", "1.1.1.1")
+	if allowed {
+		t.Fatal("penalized sender+IP should be denied")
+	}
+	if reason == "" {
+		t.Error("denied send should explain the penalty")
+	}
+}
+
+func TestVisitorStorePenalizeSenderBlocksEveryIP(t *testing.T) {
+	store := NewVisitorStore(testConfig())
+
+	store.PenalizeSender("a@example.com", "bounce rate exceeded")
+
+	if allowed, _ := store.Allow("a@example.com", "1.1.1.1"); allowed {
+		t.Fatal("sender-level penalty should block this IP")
+	}
+	if allowed, _ := store.Allow("a@example.com", "2.2.2.2"); allowed {
+		t.Fatal("sender-level penalty should block every IP for this sender")
+	}
+
+	if !store.ClearSenderPenalty("a@example.com") {
+		t.Fatal("ClearSenderPenalty should report the penalty existed")
+	}
+	if allowed, reason := store.Allow("a@example.com", "1.1.1.1"); !allowed {
+		t.Fatalf("sender should be allowed again after clearing the penalty: %s", reason)
+	}
+}
+
+func TestVisitorStorePrune(t *testing.T) {
+	store := NewVisitorStore(testConfig())
+	store.Allow("a@example.com", "1.1.1.1")
+
+	store.mu.Lock()
+	for _, v := range store.visitors {
+		v.lastSeen = time.Now().Add(-48 * time.Hour)
+	}
+	store.mu.Unlock()
+
+	store.prune(24 * time.Hour)
+
+	if len(store.Stats()) != 0 {
+		t.Error("prune should evict visitors idle longer than the timeout")
+	}
+}
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	cfg := testConfig()
+	logStore, err := NewSQLiteLogStore(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("NewSQLiteLogStore: %v", err)
+	}
+	t.Cleanup(func() { logStore.Close() })
+
+	return &Server{
+		cfg:           cfg,
+		visitors:      NewVisitorStore(cfg),
+		mailer:        &testMailer{},
+		logStore:      logStore,
+		bounceTracker: NewBounceTracker(cfg),
+	}
+}
+
+func TestDeliverSendsAndLogsEmail(t *testing.T) {
+	server := newTestServer(t)
+	email := Email{From: "a@example.com", To: []string{"b@example.com"}, Subject: "hi", Body: "hello"}
+
+	if err := server.deliver(newRequestID(), email, "1.1.1.1"); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+
+	sent := server.mailer.(*testMailer).Sent()
+	if len(sent) != 1 || sent[0].Subject != "hi" {
+		t.Fatalf("mailer recorded %+v, want one email with subject \"hi\"", sent)
+	}
+
+	records, _, err := server.logStore.Query(LogQuery{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(records) != 1 || records[0].Status != "sent" {
+		t.Fatalf("logStore recorded %+v, want one \"sent\" record", records)
+	}
+}
+
+func TestDeliverRejectsInvalidSender(t *testing.T) {
+	server := newTestServer(t)
+	err := server.deliver(newRequestID(), Email{From: "not-an-email", To: []string{"b@example.com"}}, "1.1.1.1")
+	if err == nil {
+		t.Fatal("deliver should reject an invalid sender address")
+	}
+	if statusForDeliverError(err) != http.StatusBadRequest {
+		t.Errorf("statusForDeliverError(%v) = %d, want 400", err, statusForDeliverError(err))
+	}
+}
+
+func TestDeliverRejectsOverQuota(t *testing.T) {
+	server := newTestServer(t)
+	server.cfg.VisitorEmailLimitBurst = 100
+	email := Email{From: "a@example.com", To: []string{"b@example.com"}}
+
+	for i := 0; i < server.cfg.VisitorMessageDailyLimit; i++ {
+		if err := server.deliver(newRequestID(), email, "1.1.1.1"); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+
+	err := server.deliver(newRequestID(), email, "1.1.1.1")
+	if err == nil {
+		t.Fatal("deliver beyond the quota should fail")
+	}
+	if statusForDeliverError(err) != http.StatusTooManyRequests {
+		t.Errorf("statusForDeliverError(%v) = %d, want 429", err, statusForDeliverError(err))
+	}
+}
+
+// failingMailer always fails, to exercise deliver()'s error path without
+// exposing the underlying error to the caller.
+type failingMailer struct{ err error }
+
+func (m *failingMailer) Send(email Email) (string, error) { return "", m.err }
+
+func TestDeliverDoesNotLeakMailerErrorDetails(t *testing.T) {
+	server := newTestServer(t)
+	server.mailer = &failingMailer{err: errString("dial tcp 10.0.0.1:25: connection refused")}
+
+	err := server.deliver(newRequestID(), Email{From: "a@example.com", To: []string{"b@example.com"}}, "1.1.1.1")
+	if err == nil {
+		t.Fatal("deliver should surface the mailer failure")
+	}
+	if statusForDeliverError(err) != http.StatusInternalServerError {
+		t.Errorf("statusForDeliverError(%v) = %d, want 500", err, statusForDeliverError(err))
+	}
+	if got := err.Error(); got == "dial tcp 10.0.0.1:25: connection refused" {
+		t.Errorf("deliver leaked the raw mailer error to the caller: %q", got)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+
+	sasl "github.com/emersion/go-sasl"
+	smtp "github.com/emersion/go-smtp"
+)
+
+// smtpBackend adapts inbound mail accepted by the embedded SMTP server into
+// the same delivery path used by the HTTP API, mirroring ntfy's
+// smtp_server.go gateway design.
+type smtpBackend struct {
+	server *Server
+}
+
+func runSMTPServer(server *Server) error {
+	be := &smtpBackend{server: server}
+
+	s := smtp.NewServer(be)
+	s.Addr = server.cfg.SMTPListen
+	s.Domain = "localhost"
+	s.MaxMessageBytes = server.cfg.SMTPMaxMessageBytes
+	s.MaxRecipients = 50
+	s.AllowInsecureAuth = true
+
+	return s.ListenAndServe()
+}
+
+// NewSession is called for every inbound connection. Since this bridge only
+// relays mail into the internal API, authentication (if required) accepts
+// any credentials and the SMTP client IP is used for quota purposes instead.
+func (be *smtpBackend) NewSession(c *smtp.Conn) (smtp.Session, error) {
+	return newSMTPSession(be.server, c), nil
+}
+
+// smtpSession accumulates a single inbound message before handing it to
+// Server.deliver once DATA completes.
+type smtpSession struct {
+	server *Server
+	ip     string
+
+	authenticated bool
+
+	from string
+	to   []string
+}
+
+func newSMTPSession(server *Server, c *smtp.Conn) *smtpSession {
+	ip := ""
+	if c != nil && c.Conn() != nil && c.Conn().RemoteAddr() != nil {
+		ip = remoteIP(c.Conn().RemoteAddr().String())
+	}
+	return &smtpSession{
+		server:        server,
+		ip:            ip,
+		authenticated: server.cfg.SMTPAnonymousLogin,
+	}
+}
+
+// remoteIP strips the ephemeral source port from a net.Addr string so the
+// SMTP path keys visitor quota on the same bare IP the HTTP path uses via
+// gin's c.ClientIP().
+func remoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// AuthMechanisms advertises PLAIN as the only supported mechanism; this
+// bridge has no real credential store and accepts anything via Auth below.
+func (s *smtpSession) AuthMechanisms() []string {
+	return []string{sasl.Plain}
+}
+
+func (s *smtpSession) Auth(mech string) (sasl.Server, error) {
+	return sasl.NewPlainServer(func(identity, username, password string) error {
+		s.authenticated = true
+		return nil
+	}), nil
+}
+
+func (s *smtpSession) Mail(from string, opts *smtp.MailOptions) error {
+	if !s.authenticated {
+		return smtp.ErrAuthRequired
+	}
+	s.from = from
+	return nil
+}
+
+func (s *smtpSession) Rcpt(to string, opts *smtp.RcptOptions) error {
+	if domain := s.server.cfg.SMTPAllowedRecipientDomain; domain != "" {
+		if !strings.HasSuffix(strings.ToLower(to), "@"+strings.ToLower(domain)) {
+			return fmt.Errorf("recipient domain not accepted here")
+		}
+	}
+	s.to = append(s.to, to)
+	return nil
+}
+
+// Data parses the incoming message, walks MIME multipart bodies to find a
+// text/plain part, and hands the result to the shared delivery path.
+func (s *smtpSession) Data(r io.Reader) error {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		s.server.recordSMTPResult(err)
+		return fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	body, err := extractTextPlain(msg)
+	if err != nil {
+		s.server.recordSMTPResult(err)
+		return err
+	}
+
+	email := Email{
+		From:    s.from,
+		To:      s.to,
+		Subject: msg.Header.Get("Subject"),
+		Body:    body,
+	}
+
+	err = s.server.deliver(newRequestID(), email, s.ip)
+	s.server.recordSMTPResult(err)
+	return err
+}
+
+func (s *smtpSession) Reset() {
+	s.from = ""
+	s.to = nil
+}
+
+func (s *smtpSession) Logout() error {
+	return nil
+}
+
+// extractTextPlain walks a (possibly multipart) message body looking for a
+// text/plain part. Non-multipart messages are returned as-is.
+func extractTextPlain(msg *mail.Message) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		body, err := io.ReadAll(msg.Body)
+		if err != nil {
+			return "", fmt.Errorf("failed to read message body: %w", err)
+		}
+		return string(body), nil
+	}
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read multipart message: %w", err)
+		}
+
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "text/plain") {
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return "", fmt.Errorf("failed to read text/plain part: %w", err)
+			}
+			return string(data), nil
+		}
+	}
+
+	return "", fmt.Errorf("message has no text/plain part")
+}
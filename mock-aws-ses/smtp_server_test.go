@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestRemoteIPStripsPort(t *testing.T) {
+	cases := map[string]string{
+		"1.2.3.4:54821": "1.2.3.4",
+		"[::1]:54821":   "::1",
+		"not-an-addr":   "not-an-addr",
+	}
+	for addr, want := range cases {
+		if got := remoteIP(addr); got != want {
+			t.Errorf("remoteIP(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
+
+func TestSMTPSessionRejectsDisallowedRecipientDomain(t *testing.T) {
+	server := newTestServer(t)
+	server.cfg.SMTPAllowedRecipientDomain = "example.com"
+	session := &smtpSession{server: server, authenticated: true}
+
+	if err := session.Rcpt("ok@example.com", nil); err != nil {
+		t.Errorf("Rcpt to the allowed domain should succeed: %v", err)
+	}
+	if err := session.Rcpt("nope@other.com", nil); err == nil {
+		t.Error("Rcpt to a disallowed domain should be rejected")
+	}
+}
+
+func TestSMTPSessionRequiresAuthWhenAnonymousDisallowed(t *testing.T) {
+	server := newTestServer(t)
+	server.cfg.SMTPAnonymousLogin = false
+	session := &smtpSession{server: server, authenticated: false}
+
+	if err := session.Mail("a@example.com", nil); err == nil {
+		t.Error("Mail on an unauthenticated session should be rejected when anonymous login is disallowed")
+	}
+}
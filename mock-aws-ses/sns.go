@@ -0,0 +1,266 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// snsMessage is the envelope AWS SNS POSTs for both subscription
+// confirmations and topic notifications.
+type snsMessage struct {
+	Type             string `json:"Type"`
+	MessageId        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	SubscribeURL     string `json:"SubscribeURL"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+	UnsubscribeURL   string `json:"UnsubscribeURL"`
+}
+
+// sesNotification is the SES event payload carried in snsMessage.Message for
+// Notification-type SNS messages.
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	Mail             struct {
+		MessageID string `json:"messageId"`
+		Source    string `json:"source"`
+	} `json:"mail"`
+	Bounce *struct {
+		BounceType        string `json:"bounceType"`
+		BouncedRecipients []struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"bouncedRecipients"`
+	} `json:"bounce,omitempty"`
+	Complaint *struct {
+		ComplainedRecipients []struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"complainedRecipients"`
+	} `json:"complaint,omitempty"`
+}
+
+// verifySNSSignature checks msg's signature against the certificate it
+// points to, per the AWS SNS message-signing spec. Only certificates served
+// from an amazonaws.com host are trusted, to avoid fetching attacker-chosen
+// URLs.
+func verifySNSSignature(msg *snsMessage) error {
+	certURL := msg.SigningCertURL
+	u, err := url.Parse(certURL)
+	if err != nil || u.Scheme != "https" || !isTrustedSNSHost(u.Hostname()) {
+		return fmt.Errorf("refusing to fetch signing cert from untrusted host: %s", certURL)
+	}
+
+	resp, err := http.Get(certURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch signing cert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	certPEM, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read signing cert: %w", err)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return fmt.Errorf("signing cert is not valid PEM")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse signing cert: %w", err)
+	}
+	pubKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("signing cert does not use an RSA public key")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	signed := canonicalSNSString(msg)
+
+	if msg.SignatureVersion == "2" {
+		sum := sha256.Sum256([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(pubKey, crypto.SHA256, sum[:], signature); err != nil {
+			return fmt.Errorf("signature verification failed: %w", err)
+		}
+		return nil
+	}
+
+	sum := sha1.Sum([]byte(signed))
+	if err := rsa.VerifyPKCS1v15(pubKey, crypto.SHA1, sum[:], signature); err != nil {
+		return fmt.Errorf("signature verification failed: %w", err)
+	}
+	return nil
+}
+
+// isTrustedSNSHost reports whether host is (or is a subdomain of) an
+// amazonaws.com SNS endpoint. A substring check on the full URL is not
+// sufficient here: "https://attacker.example/.amazonaws.com/cert.pem" puts
+// the substring in the path, not the host, and would otherwise be trusted.
+func isTrustedSNSHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "amazonaws.com" || strings.HasSuffix(host, ".amazonaws.com")
+}
+
+// canonicalSNSString builds the newline-delimited string SNS signs, whose
+// field order depends on the message type.
+func canonicalSNSString(msg *snsMessage) string {
+	var b strings.Builder
+	add := func(name, value string) {
+		b.WriteString(name)
+		b.WriteByte('\n')
+		b.WriteString(value)
+		b.WriteByte('\n')
+	}
+
+	if msg.Type == "SubscriptionConfirmation" || msg.Type == "UnsubscribeConfirmation" {
+		add("Message", msg.Message)
+		add("MessageId", msg.MessageId)
+		add("SubscribeURL", msg.SubscribeURL)
+		add("Timestamp", msg.Timestamp)
+		add("Token", msg.Token)
+		add("TopicArn", msg.TopicArn)
+	} else {
+		add("Message", msg.Message)
+		add("MessageId", msg.MessageId)
+		if msg.Subject != "" {
+			add("Subject", msg.Subject)
+		}
+		add("Timestamp", msg.Timestamp)
+		add("TopicArn", msg.TopicArn)
+		add("Type", msg.Type)
+	}
+
+	return b.String()
+}
+
+// bounceWindow tracks how many bounces/complaints a sender has accrued
+// within the configured rolling window.
+type bounceWindow struct {
+	from  time.Time
+	count int
+}
+
+// BounceTracker counts bounces/complaints per sender and reports when a
+// sender crosses the configured threshold within the window, so it can be
+// penalty-boxed.
+type BounceTracker struct {
+	mu      sync.Mutex
+	windows map[string]*bounceWindow
+	cfg     *Config
+}
+
+func NewBounceTracker(cfg *Config) *BounceTracker {
+	return &BounceTracker{
+		windows: make(map[string]*bounceWindow),
+		cfg:     cfg,
+	}
+}
+
+// Record notes a bounce/complaint for sender and reports whether it has now
+// crossed the configured threshold within the window.
+func (t *BounceTracker) Record(sender string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	w, ok := t.windows[sender]
+	if !ok || time.Since(w.from) >= t.cfg.BounceComplaintWindow {
+		w = &bounceWindow{from: time.Now()}
+		t.windows[sender] = w
+	}
+	w.count++
+	return w.count >= t.cfg.BounceComplaintThreshold
+}
+
+// handleSESNotification processes a single SNS message: confirming
+// subscriptions, and updating log status + bounce/complaint counters for
+// notifications.
+func (s *Server) handleSESNotification(body []byte) error {
+	var msg snsMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return fmt.Errorf("invalid SNS message: %w", err)
+	}
+
+	if err := verifySNSSignature(&msg); err != nil {
+		return fmt.Errorf("SNS signature verification failed: %w", err)
+	}
+
+	switch msg.Type {
+	case "SubscriptionConfirmation":
+		resp, err := http.Get(msg.SubscribeURL)
+		if err != nil {
+			return fmt.Errorf("failed to confirm SNS subscription: %w", err)
+		}
+		defer resp.Body.Close()
+		logger.Info("confirmed SNS subscription", Fields{"topic_arn": msg.TopicArn})
+		return nil
+
+	case "Notification":
+		var notification sesNotification
+		if err := json.Unmarshal([]byte(msg.Message), &notification); err != nil {
+			return fmt.Errorf("invalid SES notification payload: %w", err)
+		}
+		return s.applySESNotification(notification)
+
+	default:
+		logger.Warn("ignoring unrecognized SNS message type", Fields{"type": msg.Type})
+		return nil
+	}
+}
+
+// applySESNotification updates the matching log record's status and, for
+// bounces/complaints, penalty-boxes the sender once its rate crosses the
+// configured threshold.
+func (s *Server) applySESNotification(n sesNotification) error {
+	status := ""
+	switch n.NotificationType {
+	case "Bounce":
+		status = "bounced"
+	case "Complaint":
+		status = "complained"
+	case "Delivery":
+		status = "delivered"
+	default:
+		return fmt.Errorf("unknown SES notification type: %s", n.NotificationType)
+	}
+
+	if record, err := s.logStore.FindBySESMessageID(n.Mail.MessageID); err != nil {
+		logger.Warn("failed to look up log record for SES notification", Fields{"ses_message_id": n.Mail.MessageID, "error": err.Error()})
+	} else if record != nil {
+		if err := s.logStore.UpdateStatus(record.ID, status); err != nil {
+			logger.Warn("failed to update log record status", Fields{"id": record.ID, "status": status, "error": err.Error()})
+		}
+	}
+
+	if status != "bounced" && status != "complained" {
+		return nil
+	}
+
+	sender := n.Mail.Source
+	if s.bounceTracker.Record(sender) {
+		s.visitors.PenalizeSender(sender, fmt.Sprintf("%s rate exceeded threshold", n.NotificationType))
+		logger.Warn("sender penalty-boxed for excessive bounces/complaints", Fields{"sender": sender, "notification_type": n.NotificationType})
+	}
+
+	return nil
+}
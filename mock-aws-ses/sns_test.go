@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestIsTrustedSNSHost(t *testing.T) {
+	cases := []struct {
+		host  string
+		trust bool
+	}{
+		{"sns.us-east-1.amazonaws.com", true},
+		{"amazonaws.com", true},
+		{"SNS.US-EAST-1.AMAZONAWS.COM", true},
+		{"attacker.example", false},
+		{"amazonaws.com.attacker.example", false},
+		{"evil-amazonaws.com", false},
+	}
+
+	for _, c := range cases {
+		if got := isTrustedSNSHost(c.host); got != c.trust {
+			t.Errorf("isTrustedSNSHost(%q) = %v, want %v", c.host, got, c.trust)
+		}
+	}
+}
+
+func TestVerifySNSSignatureRejectsUntrustedHost(t *testing.T) {
+	// The substring ".amazonaws.com/" appears in the path, not the host --
+	// this must still be rejected.
+	msg := &snsMessage{SigningCertURL: "https://attacker.example/.amazonaws.com/cert.pem"}
+	if err := verifySNSSignature(msg); err == nil {
+		t.Fatal("verifySNSSignature should reject a cert URL whose host is not amazonaws.com")
+	}
+}
+
+func TestVerifySNSSignatureRejectsNonHTTPS(t *testing.T) {
+	msg := &snsMessage{SigningCertURL: "http://sns.us-east-1.amazonaws.com/cert.pem"}
+	if err := verifySNSSignature(msg); err == nil {
+		t.Fatal("verifySNSSignature should reject a non-https cert URL")
+	}
+}
+
+func TestBounceTrackerCrossesThreshold(t *testing.T) {
+	tracker := NewBounceTracker(testConfig())
+
+	for i := 0; i < testConfig().BounceComplaintThreshold-1; i++ {
+		if tracker.Record("a@example.com") {
+			t.Fatalf("record %d: crossed threshold too early", i)
+		}
+	}
+	if !tracker.Record("a@example.com") {
+		t.Error("record at the threshold should report crossed")
+	}
+}
+
+func TestBounceTrackerIsPerSender(t *testing.T) {
+	tracker := NewBounceTracker(testConfig())
+	tracker.Record("a@example.com")
+	if tracker.Record("b@example.com") {
+		t.Error("a different sender should have its own independent window")
+	}
+}